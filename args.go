@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"launchpad.net/gnuflag"
+)
+
+// ArgsValidator validates the positional arguments remaining after a
+// FlagSet has parsed a Command's flags.
+type ArgsValidator func(args []string) error
+
+// ExactArgs returns an ArgsValidator that requires exactly n positional
+// arguments.
+func ExactArgs(n int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("expected %d args, got %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// MinimumNArgs returns an ArgsValidator that requires at least n positional
+// arguments.
+func MinimumNArgs(n int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) < n {
+			return fmt.Errorf("expected at least %d args, got %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// RangeArgs returns an ArgsValidator that requires between min and max
+// (inclusive) positional arguments.
+func RangeArgs(min, max int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) < min || len(args) > max {
+			return fmt.Errorf("expected between %d and %d args, got %d", min, max, len(args))
+		}
+		return nil
+	}
+}
+
+// OnlyValidArgs returns an ArgsValidator that requires every positional
+// argument to appear in valid.
+func OnlyValidArgs(valid []string) ArgsValidator {
+	return func(args []string) error {
+		allowed := make(map[string]bool, len(valid))
+		for _, v := range valid {
+			allowed[v] = true
+		}
+		for _, arg := range args {
+			if !allowed[arg] {
+				return fmt.Errorf("invalid arg: %s", arg)
+			}
+		}
+		return nil
+	}
+}
+
+// ParseArgs parses args with f and validates the remaining positional
+// arguments with v, so that Command.Init implementations can replace their
+// own ad-hoc validation with a single call.
+func ParseArgs(f *gnuflag.FlagSet, args []string, v ArgsValidator) error {
+	if err := f.Parse(true, args); err != nil {
+		return err
+	}
+	return v(f.Args())
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"launchpad.net/gnuflag"
+)
+
+// Command is implemented by types that interpret command-line arguments.
+type Command interface {
+	// Info returns information about the Command.
+	Info() *Info
+
+	// Init initializes the Command before running. The command may add
+	// options to f before processing args.
+	Init(f *gnuflag.FlagSet, args []string) error
+
+	// Run runs the Command.
+	Run(ctx *Context) error
+}
+
+// Info holds some of the usual fields for a Command.
+type Info struct {
+	// Name is the Command's name.
+	Name string
+
+	// Args describes the Command's positional arguments, e.g.
+	// "<charm-name> [<service-name>]", for display alongside its options.
+	Args string
+
+	// Purpose is a short explanation of the Command's purpose.
+	Purpose string
+
+	// Doc is the long documentation for the Command.
+	Doc string
+
+	// ValidArgs, if set, lists the fixed set of values a shell completion
+	// should offer for the Command's positional arguments.
+	ValidArgs []string
+
+	// ArgsCompleter, if set, is consulted for shell completion of the
+	// Command's positional arguments instead of ValidArgs, so that
+	// candidates can depend on ctx (e.g. filesystem state) and on what the
+	// user has typed so far.
+	ArgsCompleter func(ctx *Context, partial string) []string
+}
+
+// Usage combines Name and Args to present a useful usage string for a
+// Command.
+func (i *Info) Usage() string {
+	if i.Args == "" {
+		return i.Name
+	}
+	return fmt.Sprintf("%s %s", i.Name, i.Args)
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"launchpad.net/gnuflag"
+)
+
+// completionEntry describes one command reachable from the root passed to
+// GenerateCompletion: its path (nil for the root itself, or a single
+// subcommand name), the long/short flags its Init registers, and how its
+// positional arguments should be completed.
+type completionEntry struct {
+	path      []string
+	flags     []string
+	validArgs []string
+	dynamic   bool // Info.ArgsCompleter is set; candidates come from "__complete" at runtime
+}
+
+// commandFlags returns the sorted set of flag names (both long and short
+// forms, as registered by gnuflag) that c's Init declares, probed with a
+// throwaway FlagSet.
+func commandFlags(c Command) []string {
+	f := gnuflag.NewFlagSet(c.Info().Name, gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	// Best effort: Init may fail on missing required args, but it
+	// registers its flags on f before (possibly) failing.
+	c.Init(f, nil)
+	var names []string
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		if len(flag.Name) == 1 {
+			names = append(names, "-"+flag.Name)
+		} else {
+			names = append(names, "--"+flag.Name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// commandEntry builds the completionEntry for a single command.
+func commandEntry(path []string, c Command) completionEntry {
+	info := c.Info()
+	return completionEntry{
+		path:      path,
+		flags:     commandFlags(c),
+		validArgs: info.ValidArgs,
+		dynamic:   info.ArgsCompleter != nil,
+	}
+}
+
+// walkCompletion returns root's own entry plus, if root is a SuperCommand,
+// one entry per subcommand registered against it.
+func walkCompletion(root Command) []completionEntry {
+	super, isSuper := root.(*SuperCommand)
+	rootEntry := commandEntry(nil, root)
+	if !isSuper {
+		// The "__complete" callback the generators emit is only ever
+		// dispatched by SuperCommand.Init; a plain Command has nowhere
+		// to route it, so a root.Info().ArgsCompleter would produce a
+		// broken script. Fall back to whatever static ValidArgs it has.
+		rootEntry.dynamic = false
+	}
+	entries := []completionEntry{rootEntry}
+	if isSuper {
+		for _, name := range super.commandNames() {
+			entries = append(entries, commandEntry([]string{name}, super.commands[name]))
+		}
+	}
+	return entries
+}
+
+// subcommandNames lists the names a shell should offer at the first
+// position, including the synthesized help and completion subcommands.
+func subcommandNames(entries []completionEntry) []string {
+	names := []string{"help", "completion"}
+	for _, e := range entries[1:] {
+		names = append(names, e.path[0])
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateCompletion writes a shell-completion script for shell ("bash",
+// "zsh" or "fish") to w, covering root and, if it is a SuperCommand, every
+// subcommand registered against it.
+func GenerateCompletion(shell string, root Command, w io.Writer) error {
+	name := root.Info().Name
+	entries := walkCompletion(root)
+	switch shell {
+	case "bash":
+		return generateBashCompletion(name, entries, w)
+	case "zsh":
+		return generateZshCompletion(name, entries, w)
+	case "fish":
+		return generateFishCompletion(name, entries, w)
+	}
+	return fmt.Errorf("unsupported shell: %s", shell)
+}
+
+// bashWordlist renders the compgen -W candidates for e: just its flags if
+// it has no positional-arg completion configured, or a cur-based branch
+// offering flags for a dash-prefixed word and ValidArgs/a "__complete"
+// callback otherwise.
+func bashWordlist(name string, e completionEntry, indent string) string {
+	var buf bytes.Buffer
+	if len(e.validArgs) == 0 && !e.dynamic {
+		fmt.Fprintf(&buf, "%sCOMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", indent, strings.Join(e.flags, " "))
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, "%scase \"$cur\" in\n", indent)
+	fmt.Fprintf(&buf, "%s    -*) COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") ) ;;\n", indent, strings.Join(e.flags, " "))
+	if e.dynamic {
+		fmt.Fprintf(&buf, "%s    *) COMPREPLY=( $(compgen -W \"$(%s __complete %s -- \"$cur\")\" -- \"$cur\") ) ;;\n", indent, name, completionPath(e))
+	} else {
+		fmt.Fprintf(&buf, "%s    *) COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") ) ;;\n", indent, strings.Join(e.validArgs, " "))
+	}
+	fmt.Fprintf(&buf, "%sesac\n", indent)
+	return buf.String()
+}
+
+// completionPath renders the subcommand name "__complete" is called with
+// for e ("-" for the root entry).
+func completionPath(e completionEntry) string {
+	if len(e.path) == 0 {
+		return "-"
+	}
+	return e.path[0]
+}
+
+func generateBashCompletion(name string, entries []completionEntry, w io.Writer) error {
+	fn := "_" + name + "_complete"
+	fmt.Fprintf(w, "# bash completion for %s\n", name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "    local cur\n    COMPREPLY=()\n    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	if len(entries) == 1 {
+		fmt.Fprint(w, bashWordlist(name, entries[0], "    "))
+	} else {
+		fmt.Fprintf(w, "    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+		fmt.Fprintf(w, "        COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", strings.Join(subcommandNames(entries), " "))
+		fmt.Fprintf(w, "        return\n    fi\n")
+		fmt.Fprintf(w, "    case \"${COMP_WORDS[1]}\" in\n")
+		for _, e := range entries[1:] {
+			fmt.Fprintf(w, "        %s)\n", e.path[0])
+			fmt.Fprint(w, bashWordlist(name, e, "            "))
+			fmt.Fprintf(w, "            ;;\n")
+		}
+		fmt.Fprintf(w, "    esac\n")
+	}
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, name)
+	return nil
+}
+
+// zshCompleteCall renders the zsh body offering flags and, for e,
+// ValidArgs or a "__complete" callback for positional arguments.
+func zshCompleteCall(name string, e completionEntry) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "_values 'option' %s\n", zshQuoted(e.flags))
+	if e.dynamic {
+		fmt.Fprintf(&buf, "            _values 'arg' ${(f)\"$(%s __complete %s -- $words[CURRENT])\"}\n", name, completionPath(e))
+	} else if len(e.validArgs) > 0 {
+		fmt.Fprintf(&buf, "            _values 'arg' %s\n", zshQuoted(e.validArgs))
+	}
+	return buf.String()
+}
+
+func generateZshCompletion(name string, entries []completionEntry, w io.Writer) error {
+	fn := "_" + name
+	fmt.Fprintf(w, "#compdef %s\n\n", name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	if len(entries) == 1 {
+		fmt.Fprintf(w, "    %s", zshCompleteCall(name, entries[0]))
+	} else {
+		fmt.Fprintf(w, "    local -a subcommands\n")
+		fmt.Fprintf(w, "    subcommands=(%s)\n", strings.Join(subcommandNames(entries), " "))
+		fmt.Fprintf(w, "    if (( CURRENT == 2 )); then\n        _describe 'command' subcommands\n        return\n    fi\n")
+		fmt.Fprintf(w, "    case ${words[2]} in\n")
+		for _, e := range entries[1:] {
+			fmt.Fprintf(w, "        %s) %s            ;;\n", e.path[0], zshCompleteCall(name, e))
+		}
+		fmt.Fprintf(w, "    esac\n")
+	}
+	fmt.Fprintf(w, "}\n\n%s\n", fn)
+	return nil
+}
+
+func zshQuoted(flags []string) string {
+	quoted := make([]string, len(flags))
+	for i, f := range flags {
+		quoted[i] = fmt.Sprintf("%q", f)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// fishFlagCompletions writes one "complete" line per flag in e, each
+// scoped by cond (a "-n" condition, or "" for the flat, no-subcommand
+// case).
+func fishFlagCompletions(w io.Writer, name string, e completionEntry, cond string) {
+	for _, flag := range e.flags {
+		short := strings.TrimPrefix(flag, "--")
+		short = strings.TrimPrefix(short, "-")
+		opt := "-l"
+		if !strings.HasPrefix(flag, "--") {
+			opt = "-s"
+		}
+		if cond == "" {
+			fmt.Fprintf(w, "complete -c %s %s %s\n", name, opt, short)
+		} else {
+			fmt.Fprintf(w, "complete -c %s -n \"%s\" %s %s\n", name, cond, opt, short)
+		}
+	}
+}
+
+// fishArgCompletions writes the "complete" line offering e's positional
+// arguments, scoped by cond, from ValidArgs or a "__complete" callback.
+func fishArgCompletions(w io.Writer, name string, e completionEntry, cond string) {
+	var candidates string
+	switch {
+	case e.dynamic:
+		candidates = fmt.Sprintf("(%s __complete %s -- (commandline -ct))", name, completionPath(e))
+	case len(e.validArgs) > 0:
+		candidates = strings.Join(e.validArgs, " ")
+	default:
+		return
+	}
+	if cond == "" {
+		fmt.Fprintf(w, "complete -c %s -a \"%s\"\n", name, candidates)
+	} else {
+		fmt.Fprintf(w, "complete -c %s -n \"%s\" -a \"%s\"\n", name, cond, candidates)
+	}
+}
+
+func generateFishCompletion(name string, entries []completionEntry, w io.Writer) error {
+	fmt.Fprintf(w, "# fish completion for %s\n", name)
+	if len(entries) == 1 {
+		fishFlagCompletions(w, name, entries[0], "")
+		fishArgCompletions(w, name, entries[0], "")
+		return nil
+	}
+	fmt.Fprintf(w, "complete -c %s -f -a \"%s\"\n", name, strings.Join(subcommandNames(entries), " "))
+	for _, e := range entries[1:] {
+		cond := "__fish_seen_subcommand_from " + e.path[0]
+		fishFlagCompletions(w, name, e, cond)
+		fishArgCompletions(w, name, e, cond)
+	}
+	return nil
+}
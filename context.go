@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -8,8 +9,10 @@ import (
 	"launchpad.net/juju/go/log"
 	stdlog "log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 )
 
 // Context adds a layer of indirection between a Command and its environment,
@@ -21,8 +24,11 @@ import (
 // from the agent.
 type Context struct {
 	Dir    string
+	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+
+	logger *Logger
 }
 
 // DefaultContext returns a Context suitable for use in non-hosted situations.
@@ -35,7 +41,62 @@ func DefaultContext() *Context {
 	if err != nil {
 		panic(err)
 	}
-	return &Context{abs, os.Stdout, os.Stderr}
+	return &Context{Dir: abs, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// ExecResult holds the outcome of a command run via Context.Run and its
+// variants.
+type ExecResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// Run runs name with args as a child process, with its stdio wired to
+// ctx.Stdin, ctx.Stdout and ctx.Stderr, and its working directory set to
+// ctx.Dir.
+func (ctx *Context) Run(name string, args ...string) (*ExecResult, error) {
+	return ctx.run(nil, name, args)
+}
+
+// RunPiped behaves like Run, but reads stdin from r instead of ctx.Stdin.
+func (ctx *Context) RunPiped(r io.Reader, name string, args ...string) (*ExecResult, error) {
+	return ctx.run(r, name, args)
+}
+
+// RunLogged behaves like Run, but first emits the rendered command line
+// via ctx.Logger().Debugf, matching the logging hooks already established
+// in InitLog.
+func (ctx *Context) RunLogged(name string, args ...string) (*ExecResult, error) {
+	ctx.Logger().Debugf("running: %s %s", name, strings.Join(args, " "))
+	return ctx.run(nil, name, args)
+}
+
+// run is the shared implementation behind Run, RunPiped and RunLogged.
+func (ctx *Context) run(stdin io.Reader, name string, args []string) (*ExecResult, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = ctx.Dir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	} else {
+		cmd.Stdin = ctx.Stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(ctx.Stdout, &stdout)
+	cmd.Stderr = io.MultiWriter(ctx.Stderr, &stderr)
+	result := &ExecResult{}
+	err := cmd.Run()
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			result.ExitCode = status.ExitStatus()
+		}
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // AbsPath returns an absolute representation of path, with relative paths
@@ -47,20 +108,32 @@ func (ctx *Context) AbsPath(path string) string {
 	return filepath.Join(ctx.Dir, path)
 }
 
-// InitLog sets up logging to a file or to ctx.Stderr as directed.
-func (ctx *Context) InitLog(verbose bool, debug bool, logfile string) (err error) {
-	log.Debug = debug
+// InitLog sets up level-aware logging to a file or to ctx.Stderr as
+// directed by cfg, with optional size-based rotation of the logfile, and
+// makes the result available via Context.Logger(). It also keeps the
+// package-level log.Target wired up to the same destination in text mode,
+// for code that still logs through log.Debugf directly.
+func (ctx *Context) InitLog(cfg LogConfig) (err error) {
+	if cfg.Level == levelUnset {
+		cfg.Level = DefaultLevel
+	}
+	log.Debug = cfg.Level <= DEBUG
+	format := cfg.Format
+	if format == "" {
+		format = "text"
+	}
 	var target io.Writer
-	if logfile != "" {
-		path := ctx.AbsPath(logfile)
-		target, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if cfg.Logfile != "" {
+		path := ctx.AbsPath(cfg.Logfile)
+		target, err = newRotatingWriter(path, cfg.MaxSize, cfg.Backups)
 		if err != nil {
 			return
 		}
-	} else if verbose || debug {
+	} else {
 		target = ctx.Stderr
 	}
-	if target != nil {
+	ctx.logger = &Logger{out: target, format: format, level: cfg.Level}
+	if format == "text" {
 		log.Target = stdlog.New(target, "", stdlog.LstdFlags)
 	} else {
 		log.Target = nil
@@ -68,6 +141,16 @@ func (ctx *Context) InitLog(verbose bool, debug bool, logfile string) (err error
 	return
 }
 
+// Logger returns the typed, leveled logger set up by InitLog, so commands
+// can log structured records instead of reaching into the package-level
+// log.Target.
+func (ctx *Context) Logger() *Logger {
+	if ctx.logger == nil {
+		ctx.logger = &Logger{out: ctx.Stderr, format: "text", level: DefaultLevel}
+	}
+	return ctx.logger
+}
+
 // Main will Parse and Run a Command, and return a process exit code. args
 // should contain flags and arguments only (and not the top-level command name).
 func Main(c Command, ctx *Context, args []string) int {
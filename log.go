@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"launchpad.net/gnuflag"
+)
+
+// Level identifies the severity of a log record, in increasing order of
+// severity. The zero value is deliberately not a usable level, so that a
+// zero-value LogConfig{} is recognizable as "unset" rather than silently
+// meaning "log everything"; InitLog and AddFlags default an unset Level to
+// DefaultLevel.
+type Level int
+
+const (
+	levelUnset Level = iota
+	TRACE
+	DEBUG
+	INFO
+	WARN
+	ERROR
+)
+
+// DefaultLevel is the severity InitLog and AddFlags use when a LogConfig
+// doesn't specify one. INFO, not WARN, so that Context.Logger().Infof
+// output - the level hosted agents are expected to forward - isn't
+// silently dropped by default.
+const DefaultLevel = INFO
+
+// String returns the canonical upper-case name of l.
+func (l Level) String() string {
+	switch l {
+	case TRACE:
+		return "TRACE"
+	case DEBUG:
+		return "DEBUG"
+	case INFO:
+		return "INFO"
+	case WARN:
+		return "WARN"
+	case ERROR:
+		return "ERROR"
+	}
+	return "UNKNOWN"
+}
+
+// ParseLevel converts a level name, in any case, to a Level.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(name) {
+	case "TRACE":
+		return TRACE, nil
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// LogConfig controls the logging set up by Context.InitLog.
+type LogConfig struct {
+	// Level is the minimum severity that will be logged.
+	Level Level
+
+	// Logfile, if set, is the (possibly relative) path logs are written
+	// to, instead of ctx.Stderr.
+	Logfile string
+
+	// Format selects "text" (the default) or "json" record rendering.
+	Format string
+
+	// MaxSize is the size in bytes a Logfile may reach before it is
+	// rotated. Zero or negative disables rotation.
+	MaxSize int64
+
+	// Backups is the number of rotated logfiles (logfile.1..logfile.N)
+	// retained once rotation is enabled.
+	Backups int
+}
+
+// levelFlag adapts a *Level to the gnuflag.Value interface so it can be
+// set by name (e.g. "--log-level=debug") on a FlagSet.
+type levelFlag struct {
+	level *Level
+}
+
+func (v levelFlag) String() string {
+	if v.level == nil || *v.level == levelUnset {
+		return ""
+	}
+	return v.level.String()
+}
+
+func (v levelFlag) Set(value string) error {
+	level, err := ParseLevel(value)
+	if err != nil {
+		return err
+	}
+	*v.level = level
+	return nil
+}
+
+// AddFlags registers the --log-level, --log-file, --log-format,
+// --log-max-size and --log-backups flags that drive cfg, so a Command's
+// Init can call cfg.AddFlags(f) once and later pass cfg to
+// ctx.InitLog(cfg) instead of re-declaring these flags itself.
+func (cfg *LogConfig) AddFlags(f *gnuflag.FlagSet) {
+	if cfg.Format == "" {
+		cfg.Format = "text"
+	}
+	if cfg.Level == levelUnset {
+		cfg.Level = DefaultLevel
+	}
+	f.Var(levelFlag{&cfg.Level}, "log-level", "log level: trace, debug, info, warn or error")
+	f.StringVar(&cfg.Logfile, "log-file", cfg.Logfile, "path to a file to log to, instead of stderr")
+	f.StringVar(&cfg.Format, "log-format", cfg.Format, "log record format: text or json")
+	f.Int64Var(&cfg.MaxSize, "log-max-size", cfg.MaxSize, "rotate the logfile after it reaches this many bytes (0 disables rotation)")
+	f.IntVar(&cfg.Backups, "log-backups", cfg.Backups, "number of rotated logfiles to keep")
+}
+
+// Logger is a leveled logger returned by Context.Logger(). Fields attached
+// with With are carried into every subsequent record, so commands can
+// build up context (e.g. a request id) once and log.With(...).Infof(...)
+// from then on.
+type Logger struct {
+	out    io.Writer
+	format string
+	level  Level
+	fields map[string]interface{}
+}
+
+// With returns a copy of l that additionally carries the given field in
+// every record it logs.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	fields := make(map[string]interface{}, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	return &Logger{out: l.out, format: l.format, level: l.level, fields: fields}
+}
+
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if l.format == "json" {
+		record := map[string]interface{}{
+			"ts":    time.Now().Format(time.RFC3339),
+			"level": level.String(),
+			"msg":   msg,
+		}
+		if len(l.fields) > 0 {
+			record["fields"] = l.fields
+		}
+		enc := json.NewEncoder(l.out)
+		enc.Encode(record)
+		return
+	}
+	fmt.Fprintf(l.out, "%s %s %s", time.Now().Format(time.RFC3339), level, msg)
+	if len(l.fields) > 0 {
+		fmt.Fprintf(l.out, " %v", l.fields)
+	}
+	fmt.Fprintln(l.out)
+}
+
+// Tracef logs a TRACE-level record.
+func (l *Logger) Tracef(format string, args ...interface{}) { l.log(TRACE, format, args...) }
+
+// Debugf logs a DEBUG-level record.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.log(DEBUG, format, args...) }
+
+// Infof logs an INFO-level record.
+func (l *Logger) Infof(format string, args ...interface{}) { l.log(INFO, format, args...) }
+
+// Warnf logs a WARN-level record.
+func (l *Logger) Warnf(format string, args ...interface{}) { l.log(WARN, format, args...) }
+
+// Errorf logs an ERROR-level record.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.log(ERROR, format, args...) }
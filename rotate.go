@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// rotatingWriter is an io.Writer over a file that renames the file to
+// path.1, path.2, ... path.N (shifting older backups up by one and
+// discarding anything past backups) whenever a Write would push it past
+// maxSize. maxSize <= 0 disables rotation entirely.
+type rotatingWriter struct {
+	path    string
+	maxSize int64
+	backups int
+
+	file *os.File
+	size int64
+}
+
+// newRotatingWriter opens (creating if necessary) the file at path and
+// returns a writer that rotates it as described above.
+func newRotatingWriter(path string, maxSize int64, backups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{path: path, maxSize: maxSize, backups: backups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	if w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate closes the current file, shifts path.1..path.(N-1) up to
+// path.2..path.N (discarding anything beyond w.backups), moves path itself
+// to path.1, then reopens path fresh.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", w.path, w.backups))
+	for n := w.backups - 1; n >= 1; n-- {
+		older := fmt.Sprintf("%s.%d", w.path, n+1)
+		newer := fmt.Sprintf("%s.%d", w.path, n)
+		if _, err := os.Stat(newer); err == nil {
+			if err := os.Rename(newer, older); err != nil {
+				return err
+			}
+		}
+	}
+	if w.backups > 0 {
+		if err := os.Rename(w.path, fmt.Sprintf("%s.1", w.path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+		// No backups are kept, so there is nothing to rename path to;
+		// remove it outright so open() starts a fresh, empty file and
+		// maxSize is actually enforced instead of growing the same file
+		// forever.
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the underlying file.
+func (w *rotatingWriter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingWriterRotatesOnMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	w, err := newRotatingWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	for _, chunk := range []string{"0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write(%q): %v", chunk, err)
+		}
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(path): %v", err)
+	}
+	if want := []byte("ABCDEFGHIJ"); !bytes.Equal(got, want) {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+	got1, err := ioutil.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("ReadFile(path.1): %v", err)
+	}
+	if want := []byte("abcdefghij"); !bytes.Equal(got1, want) {
+		t.Errorf("path.1 = %q, want %q", got1, want)
+	}
+	got2, err := ioutil.ReadFile(path + ".2")
+	if err != nil {
+		t.Fatalf("ReadFile(path.2): %v", err)
+	}
+	if want := []byte("0123456789"); !bytes.Equal(got2, want) {
+		t.Errorf("path.2 = %q, want %q", got2, want)
+	}
+}
+
+func TestRotatingWriterDiscardsBeyondBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	w, err := newRotatingWriter(path, 10, 1)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	for _, chunk := range []string{"0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write(%q): %v", chunk, err)
+		}
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("path.2 should not exist with backups=1, stat err = %v", err)
+	}
+	got1, err := ioutil.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("ReadFile(path.1): %v", err)
+	}
+	if want := []byte("abcdefghij"); !bytes.Equal(got1, want) {
+		t.Errorf("path.1 = %q, want %q", got1, want)
+	}
+}
+
+func TestRotatingWriterNoBackupsEnforcesMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	w, err := newRotatingWriter(path, 10, 0)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	for _, chunk := range []string{"0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write(%q): %v", chunk, err)
+		}
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(path): %v", err)
+	}
+	if want := []byte("ABCDEFGHIJ"); !bytes.Equal(got, want) {
+		t.Errorf("path = %q, want %q (old content should have been discarded, not appended to)", got, want)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("path.1 should not exist with backups=0, stat err = %v", err)
+	}
+}
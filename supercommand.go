@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"launchpad.net/gnuflag"
+)
+
+// SuperCommand is a Command that dispatches to other Commands registered
+// against it, in the manner of tools such as "git" or "juju" (e.g. "juju
+// bootstrap", "juju deploy ..."). Global flags are parsed up to the first
+// non-flag token, which names the subcommand; everything after that is
+// handed to the chosen subcommand's own Init and Run.
+type SuperCommand struct {
+	Name    string
+	Purpose string
+	Doc     string
+
+	commands   map[string]Command
+	subcommand Command
+}
+
+// NewSuperCommand returns an initialized SuperCommand, ready to have
+// Commands registered against it via Register.
+func NewSuperCommand(name, purpose, doc string) *SuperCommand {
+	return &SuperCommand{
+		Name:     name,
+		Purpose:  purpose,
+		Doc:      doc,
+		commands: make(map[string]Command),
+	}
+}
+
+// Register makes subcommand available under its own Info().Name.
+func (c *SuperCommand) Register(subcommand Command) {
+	c.commands[subcommand.Info().Name] = subcommand
+}
+
+// Info returns a description of the SuperCommand itself; the usage line
+// documents the nested-subcommand form rather than any options of the
+// SuperCommand's own, which it has none of.
+func (c *SuperCommand) Info() *Info {
+	return &Info{
+		Name:    c.Name,
+		Args:    "<command> [options]",
+		Purpose: c.Purpose,
+		Doc:     c.Doc,
+	}
+}
+
+// Init parses global flags up to the first non-flag token, which is taken
+// to be the name of a registered subcommand (or the synthesized "help"
+// subcommand); any remaining arguments are passed to that subcommand's own
+// Init.
+func (c *SuperCommand) Init(f *gnuflag.FlagSet, args []string) error {
+	if err := f.Parse(false, args); err != nil {
+		return err
+	}
+	args = f.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("no command specified\n\n%s", c.listing())
+	}
+	name, subargs := args[0], args[1:]
+	if name == "help" {
+		c.subcommand = &helpCommand{super: c}
+	} else if name == "completion" {
+		c.subcommand = &completionCommand{super: c}
+	} else if name == "__complete" {
+		c.subcommand = &completeArgsCommand{super: c}
+	} else if subcommand, ok := c.commands[name]; ok {
+		c.subcommand = subcommand
+	} else {
+		return fmt.Errorf("unrecognized command: %s %s\n\n%s", c.Name, name, c.listing())
+	}
+	subf := gnuflag.NewFlagSet(c.subcommand.Info().Name, gnuflag.ContinueOnError)
+	subf.SetOutput(ioutil.Discard)
+	return c.subcommand.Init(subf, subargs)
+}
+
+// Run runs whichever subcommand was selected by Init.
+func (c *SuperCommand) Run(ctx *Context) error {
+	if c.subcommand == nil {
+		return fmt.Errorf("no command specified")
+	}
+	return c.subcommand.Run(ctx)
+}
+
+// commandNames returns the names of registered subcommands, sorted, for
+// use in the top-level listing.
+func (c *SuperCommand) commandNames() []string {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listing renders the "name  purpose" pairs for all registered commands,
+// used both by the built-in help command and by unrecognized-command
+// errors.
+func (c *SuperCommand) listing() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "commands:\n")
+	for _, name := range c.commandNames() {
+		fmt.Fprintf(&buf, "    %-12s  %s\n", name, c.commands[name].Info().Purpose)
+	}
+	return buf.String()
+}
+
+// helpCommand is synthesized by SuperCommand to provide per-subcommand and
+// top-level usage information; it is not registered like an ordinary
+// Command and so cannot be overridden.
+type helpCommand struct {
+	super *SuperCommand
+	topic string
+}
+
+func (h *helpCommand) Info() *Info {
+	return &Info{Name: "help", Purpose: "show help on a command or list the available commands"}
+}
+
+func (h *helpCommand) Init(f *gnuflag.FlagSet, args []string) error {
+	if len(args) > 0 {
+		h.topic = args[0]
+	}
+	return nil
+}
+
+func (h *helpCommand) Run(ctx *Context) error {
+	if h.topic == "" {
+		fmt.Fprintf(ctx.Stdout, "usage: %s\n", h.super.Info().Usage())
+		if h.super.Purpose != "" {
+			fmt.Fprintf(ctx.Stdout, "purpose: %s\n", h.super.Purpose)
+		}
+		fmt.Fprintf(ctx.Stdout, "\n%s", h.super.listing())
+		return nil
+	}
+	subcommand, ok := h.super.commands[h.topic]
+	if !ok {
+		return fmt.Errorf("unrecognized command: %s %s", h.super.Name, h.topic)
+	}
+	f := gnuflag.NewFlagSet(subcommand.Info().Name, gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	// Best effort: some commands require arguments we don't have, but
+	// Init still registers its flags on f before (possibly) failing.
+	subcommand.Init(f, nil)
+	printUsage(subcommand, f, ctx.Stdout)
+	return nil
+}
+
+// completionCommand is synthesized by SuperCommand, hidden from the
+// top-level listing, to emit a shell-completion script covering the
+// SuperCommand and everything registered against it: "eval \"$(mytool
+// completion bash)\"".
+type completionCommand struct {
+	super *SuperCommand
+	shell string
+}
+
+func (h *completionCommand) Info() *Info {
+	return &Info{Name: "completion", Args: "<bash|zsh|fish>", Purpose: "generate shell completion script"}
+}
+
+func (h *completionCommand) Init(f *gnuflag.FlagSet, args []string) error {
+	if err := ParseArgs(f, args, ExactArgs(1)); err != nil {
+		return err
+	}
+	h.shell = f.Args()[0]
+	return nil
+}
+
+func (h *completionCommand) Run(ctx *Context) error {
+	return GenerateCompletion(h.shell, h.super, ctx.Stdout)
+}
+
+// completeArgsCommand is synthesized by SuperCommand, hidden from the
+// top-level listing, as the runtime half of dynamic completion: the shell
+// scripts GenerateCompletion emits call "<name> __complete <subcommand> --
+// <partial>" to ask a registered subcommand's Info.ArgsCompleter (falling
+// back to its Info.ValidArgs) which positional-arg candidates to offer.
+type completeArgsCommand struct {
+	super   *SuperCommand
+	name    string
+	partial string
+}
+
+func (h *completeArgsCommand) Info() *Info {
+	return &Info{Name: "__complete", Purpose: "internal: list completion candidates for a subcommand's positional args"}
+}
+
+func (h *completeArgsCommand) Init(f *gnuflag.FlagSet, args []string) error {
+	if err := MinimumNArgs(1)(args); err != nil {
+		return err
+	}
+	h.name = args[0]
+	rest := args[1:]
+	if len(rest) > 0 && rest[0] == "--" {
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		h.partial = rest[0]
+	}
+	return nil
+}
+
+func (h *completeArgsCommand) Run(ctx *Context) error {
+	subcommand, ok := h.super.commands[h.name]
+	if !ok {
+		return nil
+	}
+	info := subcommand.Info()
+	candidates := info.ValidArgs
+	if info.ArgsCompleter != nil {
+		candidates = info.ArgsCompleter(ctx, h.partial)
+	}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, h.partial) {
+			fmt.Fprintln(ctx.Stdout, candidate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"launchpad.net/gnuflag"
+)
+
+// echoCommand is a minimal Command used to exercise SuperCommand dispatch.
+type echoCommand struct {
+	ran  bool
+	args []string
+}
+
+func (c *echoCommand) Info() *Info {
+	return &Info{Name: "echo", Purpose: "echo its arguments"}
+}
+
+func (c *echoCommand) Init(f *gnuflag.FlagSet, args []string) error {
+	c.args = args
+	return nil
+}
+
+func (c *echoCommand) Run(ctx *Context) error {
+	c.ran = true
+	return nil
+}
+
+func newTestContext() (*Context, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &Context{Dir: ".", Stdout: &stdout, Stderr: &stderr}, &stdout, &stderr
+}
+
+func TestSuperCommandDispatchesToRegisteredSubcommand(t *testing.T) {
+	super := NewSuperCommand("tool", "a tool", "")
+	echo := &echoCommand{}
+	super.Register(echo)
+
+	f := gnuflag.NewFlagSet("tool", gnuflag.ContinueOnError)
+	if err := super.Init(f, []string{"echo", "hello", "world"}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	ctx, _, _ := newTestContext()
+	if err := super.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !echo.ran {
+		t.Error("registered subcommand was not run")
+	}
+	if got, want := echo.args, []string{"hello", "world"}; !equalStrings(got, want) {
+		t.Errorf("subcommand args = %v, want %v", got, want)
+	}
+}
+
+func TestSuperCommandUnknownCommandListsRegistered(t *testing.T) {
+	super := NewSuperCommand("tool", "a tool", "")
+	super.Register(&echoCommand{})
+
+	f := gnuflag.NewFlagSet("tool", gnuflag.ContinueOnError)
+	err := super.Init(f, []string{"bogus"})
+	if err == nil {
+		t.Fatal("Init succeeded for an unregistered subcommand, want error")
+	}
+	if !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("error %q does not name the unrecognized command", err)
+	}
+	if !strings.Contains(err.Error(), "echo") {
+		t.Errorf("error %q does not list the registered commands", err)
+	}
+}
+
+func TestSuperCommandHelpListsRegistered(t *testing.T) {
+	super := NewSuperCommand("tool", "a tool", "")
+	super.Register(&echoCommand{})
+
+	f := gnuflag.NewFlagSet("tool", gnuflag.ContinueOnError)
+	if err := super.Init(f, []string{"help"}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	ctx, stdout, _ := newTestContext()
+	if err := super.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "echo") {
+		t.Errorf("help output %q does not list the echo subcommand", stdout.String())
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}